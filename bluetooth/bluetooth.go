@@ -0,0 +1,236 @@
+// Package bluetooth streams classic and BLE device discoveries from
+// BlueZ over D-Bus, replacing the old hcitool-scan-and-regex approach so
+// that sightings (including RSSI and advertised service data, which
+// hcitool never exposed) arrive as soon as BlueZ sees them.
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	busName           = "org.bluez"
+	adapter1Interface = "org.bluez.Adapter1"
+	device1Interface  = "org.bluez.Device1"
+)
+
+// Observation is a single device sighting reported by BlueZ.
+type Observation struct {
+	MAC              string
+	Name             string
+	RSSI             int16
+	TxPower          int16
+	AddressType      string
+	ServiceUUIDs     []string
+	ManufacturerData map[uint16][]byte
+	Timestamp        time.Time
+}
+
+// Scanner drives discovery on a single BlueZ adapter and turns its
+// InterfacesAdded/PropertiesChanged signals into a stream of
+// Observations.
+type Scanner struct {
+	conn        *dbus.Conn
+	adapterPath dbus.ObjectPath
+	signals     chan *dbus.Signal
+	discoveries chan Observation
+	done        chan struct{}
+}
+
+// NewScanner opens the system bus, starts discovery on adapterID (e.g.
+// "hci0") and begins streaming discoveries. Call Discoveries to consume
+// them and Close to stop discovery and release the bus connection.
+func NewScanner(adapterID string) (*Scanner, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("bluetooth: connecting to system bus: %v", err)
+	}
+
+	adapterPath := dbus.ObjectPath("/org/bluez/" + adapterID)
+	adapter := conn.Object(busName, adapterPath)
+	if call := adapter.Call(adapter1Interface+".StartDiscovery", 0); call.Err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("bluetooth: starting discovery on %s: %v", adapterID, call.Err)
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath("/org/bluez"),
+		dbus.WithMatchInterface("org.freedesktop.DBus.ObjectManager"),
+		dbus.WithMatchMember("InterfacesAdded"),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("bluetooth: watching InterfacesAdded: %v", err)
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("bluetooth: watching PropertiesChanged: %v", err)
+	}
+
+	s := &Scanner{
+		conn:        conn,
+		adapterPath: adapterPath,
+		signals:     make(chan *dbus.Signal, 32),
+		discoveries: make(chan Observation, 32),
+		done:        make(chan struct{}),
+	}
+
+	conn.Signal(s.signals)
+	go s.run()
+
+	return s, nil
+}
+
+// Discoveries returns the channel on which new Observations are
+// delivered. It is closed when the Scanner is closed.
+func (s *Scanner) Discoveries() <-chan Observation {
+	return s.discoveries
+}
+
+// Close stops discovery and closes the underlying bus connection.
+func (s *Scanner) Close() error {
+	adapter := s.conn.Object(busName, s.adapterPath)
+	adapter.Call(adapter1Interface+".StopDiscovery", 0)
+
+	close(s.done)
+	return s.conn.Close()
+}
+
+func (s *Scanner) run() {
+	defer close(s.discoveries)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case sig, ok := <-s.signals:
+			if !ok {
+				return
+			}
+
+			obs, ok := parseSignal(sig)
+			if !ok {
+				continue
+			}
+
+			select {
+			case s.discoveries <- obs:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func parseSignal(sig *dbus.Signal) (Observation, bool) {
+	switch sig.Name {
+	case "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
+		return parseInterfacesAdded(sig)
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		return parsePropertiesChanged(sig)
+	default:
+		return Observation{}, false
+	}
+}
+
+func parseInterfacesAdded(sig *dbus.Signal) (Observation, bool) {
+	if len(sig.Body) != 2 {
+		return Observation{}, false
+	}
+
+	interfaces, ok := sig.Body[1].(map[string]map[string]dbus.Variant)
+	if !ok {
+		return Observation{}, false
+	}
+
+	props, ok := interfaces[device1Interface]
+	if !ok {
+		return Observation{}, false
+	}
+
+	return observationFromProps(props), true
+}
+
+func parsePropertiesChanged(sig *dbus.Signal) (Observation, bool) {
+	if len(sig.Body) < 2 {
+		return Observation{}, false
+	}
+
+	iface, ok := sig.Body[0].(string)
+	if !ok || iface != device1Interface {
+		return Observation{}, false
+	}
+
+	changed, ok := sig.Body[1].(map[string]dbus.Variant)
+	if !ok {
+		return Observation{}, false
+	}
+
+	obs := observationFromProps(changed)
+	if obs.MAC == "" {
+		obs.MAC = macFromDevicePath(sig.Path)
+	}
+
+	return obs, obs.MAC != ""
+}
+
+// macFromDevicePath recovers a MAC address from a BlueZ device object
+// path of the form /org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF.
+func macFromDevicePath(path dbus.ObjectPath) string {
+	idx := strings.LastIndex(string(path), "dev_")
+	if idx == -1 {
+		return ""
+	}
+
+	return strings.ToLower(strings.ReplaceAll(string(path)[idx+len("dev_"):], "_", ":"))
+}
+
+func observationFromProps(props map[string]dbus.Variant) Observation {
+	obs := Observation{Timestamp: time.Now()}
+
+	if v, ok := props["Address"]; ok {
+		if addr, ok := v.Value().(string); ok {
+			// BlueZ reports Address upper-case; macFromDevicePath (the
+			// PropertiesChanged fallback) lower-cases. Normalize
+			// here so both paths agree on one key, or every device
+			// ends up stored twice and re-seen as "new" forever.
+			obs.MAC = strings.ToLower(addr)
+		}
+	}
+	if v, ok := props["Name"]; ok {
+		obs.Name, _ = v.Value().(string)
+	} else if v, ok := props["Alias"]; ok {
+		obs.Name, _ = v.Value().(string)
+	}
+	if v, ok := props["RSSI"]; ok {
+		obs.RSSI, _ = v.Value().(int16)
+	}
+	if v, ok := props["TxPower"]; ok {
+		obs.TxPower, _ = v.Value().(int16)
+	}
+	if v, ok := props["AddressType"]; ok {
+		obs.AddressType, _ = v.Value().(string)
+	}
+	if v, ok := props["UUIDs"]; ok {
+		obs.ServiceUUIDs, _ = v.Value().([]string)
+	}
+	if v, ok := props["ManufacturerData"]; ok {
+		if raw, ok := v.Value().(map[uint16]dbus.Variant); ok {
+			obs.ManufacturerData = make(map[uint16][]byte, len(raw))
+			for id, data := range raw {
+				if b, ok := data.Value().([]byte); ok {
+					obs.ManufacturerData[id] = b
+				}
+			}
+		}
+	}
+
+	return obs
+}
@@ -0,0 +1,40 @@
+package bluetooth
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestMacFromDevicePath(t *testing.T) {
+	tests := []struct {
+		path dbus.ObjectPath
+		want string
+	}{
+		{"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF", "aa:bb:cc:dd:ee:ff"},
+		{"/org/bluez/hci1/dev_00_11_22_33_44_55", "00:11:22:33:44:55"},
+		{"/org/bluez/hci0", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := macFromDevicePath(tt.path); got != tt.want {
+			t.Errorf("macFromDevicePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestObservationFromPropsNormalizesMAC guards against the Address
+// property (always upper-case from BlueZ) and the macFromDevicePath
+// fallback (lower-case) disagreeing on case, which would make every
+// RSSI-only update look like a brand-new device.
+func TestObservationFromPropsNormalizesMAC(t *testing.T) {
+	props := map[string]dbus.Variant{
+		"Address": dbus.MakeVariant("AA:BB:CC:DD:EE:FF"),
+	}
+
+	obs := observationFromProps(props)
+	if want := "aa:bb:cc:dd:ee:ff"; obs.MAC != want {
+		t.Errorf("observationFromProps: MAC = %q, want %q", obs.MAC, want)
+	}
+}
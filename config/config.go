@@ -0,0 +1,126 @@
+// Package config defines the wardriver's runtime configuration and
+// watches it for changes on disk, so parameters that used to be
+// constants scattered across the daemon can be tuned without a
+// restart.
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotifyStep is one step of the LED notification sequence played when
+// something interesting happens: set the LED to Color and hold it for
+// Duration before moving to the next step.
+type NotifyStep struct {
+	Color    string
+	Duration Duration
+}
+
+// Config holds every tunable that used to be a constant in main. Fields
+// are documented with whether a running daemon picks up a change to
+// them immediately (live) or only on the next start (startup-only) -
+// the latter covers parameters that are wired into an already-open
+// connection (a Store, a bluetooth.Scanner) and can't be swapped
+// without tearing that connection down.
+type Config struct {
+	// CooldownWindow is how long a known device is ignored for after
+	// being seen, before it's reported again. Live.
+	CooldownWindow Duration
+
+	// OledLines is the number of lines kept in the OLED scroll buffer.
+	// Live.
+	OledLines int
+
+	// NotifySequence is the LED color/duration sequence played on a
+	// new or re-surfaced device. Live.
+	NotifySequence []NotifyStep
+
+	// ScanBackend is the BlueZ adapter to scan with, e.g. "hci0".
+	// Startup-only.
+	ScanBackend string
+
+	// DBBackend and DBDSN select the persistence backend. Startup-only.
+	DBBackend string
+	DBDSN     string
+
+	// MACAllowList, if non-empty, restricts processing to these MAC
+	// addresses; MACDenyList excludes MACs even if they're on the
+	// allow list. Live.
+	MACAllowList []string
+	MACDenyList  []string
+}
+
+// Default returns the configuration that matches the daemon's old,
+// hard-coded behavior.
+func Default() Config {
+	return Config{
+		CooldownWindow: Duration(5 * time.Hour),
+		OledLines:      8,
+		NotifySequence: []NotifyStep{
+			{Color: "0x0000ff", Duration: Duration(0)},
+			{Color: "0x000000", Duration: Duration(0)},
+		},
+		ScanBackend: "hci0",
+		DBBackend:   "diskv",
+		DBDSN:       "diskv-data",
+	}
+}
+
+// Validate rejects configurations that would leave the daemon unable
+// to run, so a bad reload can be refused and the previous config kept.
+func (c Config) Validate() error {
+	if c.OledLines <= 0 {
+		return fmt.Errorf("config: oled-lines must be positive, got %d", c.OledLines)
+	}
+
+	if c.CooldownWindow.Duration() < 0 {
+		return fmt.Errorf("config: cooldown-window must not be negative")
+	}
+
+	if len(c.NotifySequence) == 0 {
+		return fmt.Errorf("config: notify-sequence must not be empty")
+	}
+	for i, step := range c.NotifySequence {
+		if step.Duration.Duration() < 0 {
+			return fmt.Errorf("config: notify-sequence[%d] has a negative duration", i)
+		}
+	}
+
+	switch c.DBBackend {
+	case "diskv", "sqlite", "postgres":
+	default:
+		return fmt.Errorf("config: unknown db-backend %q", c.DBBackend)
+	}
+
+	for _, mac := range c.MACDenyList {
+		for _, allowed := range c.MACAllowList {
+			if mac == allowed {
+				return fmt.Errorf("config: %s is on both the allow and deny list", mac)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Allows reports whether mac should be processed under this config.
+func (c Config) Allows(mac string) bool {
+	for _, denied := range c.MACDenyList {
+		if mac == denied {
+			return false
+		}
+	}
+
+	if len(c.MACAllowList) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.MACAllowList {
+		if mac == allowed {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package config
+
+import "testing"
+
+func TestConfigValidate(t *testing.T) {
+	valid := Default()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Default() failed validation: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		mut  func(c *Config)
+	}{
+		{"zero oled-lines", func(c *Config) { c.OledLines = 0 }},
+		{"negative cooldown", func(c *Config) { c.CooldownWindow = Duration(-1) }},
+		{"empty notify-sequence", func(c *Config) { c.NotifySequence = nil }},
+		{"negative notify-step duration", func(c *Config) { c.NotifySequence = []NotifyStep{{Duration: Duration(-1)}} }},
+		{"unknown db-backend", func(c *Config) { c.DBBackend = "mongo" }},
+		{"mac on both lists", func(c *Config) {
+			c.MACAllowList = []string{"aa:bb:cc:dd:ee:ff"}
+			c.MACDenyList = []string{"aa:bb:cc:dd:ee:ff"}
+		}},
+	}
+
+	for _, tt := range tests {
+		cfg := Default()
+		tt.mut(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected Validate to reject the config", tt.name)
+		}
+	}
+}
+
+func TestConfigAllows(t *testing.T) {
+	cfg := Default()
+	if !cfg.Allows("aa:bb:cc:dd:ee:ff") {
+		t.Error("an empty allow/deny list should allow everything")
+	}
+
+	cfg.MACDenyList = []string{"aa:bb:cc:dd:ee:ff"}
+	if cfg.Allows("aa:bb:cc:dd:ee:ff") {
+		t.Error("a denied MAC should not be allowed")
+	}
+
+	cfg = Default()
+	cfg.MACAllowList = []string{"aa:bb:cc:dd:ee:ff"}
+	if !cfg.Allows("aa:bb:cc:dd:ee:ff") {
+		t.Error("an allow-listed MAC should be allowed")
+	}
+	if cfg.Allows("11:22:33:44:55:66") {
+		t.Error("a non-allow-listed MAC should be denied once an allow list is set")
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that unmarshals from either a JSON string
+// ("5h", "200ms", ...) or a plain number of nanoseconds, so config.json
+// can use whichever is more readable for a given field.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var asString string
+	if err := json.Unmarshal(data, &asString); err == nil {
+		parsed, err := time.ParseDuration(asString)
+		if err != nil {
+			return fmt.Errorf("config: parsing duration %q: %v", asString, err)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+
+	var asNanos int64
+	if err := json.Unmarshal(data, &asNanos); err != nil {
+		return fmt.Errorf("config: duration must be a string or a number of nanoseconds: %v", err)
+	}
+	*d = Duration(asNanos)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want time.Duration
+		ok   bool
+	}{
+		{`"5h"`, 5 * time.Hour, true},
+		{`"200ms"`, 200 * time.Millisecond, true},
+		{`0`, 0, true},
+		{`1000000000`, time.Second, true},
+		{`"not-a-duration"`, 0, false},
+		{`true`, 0, false},
+	}
+
+	for _, tt := range tests {
+		var d Duration
+		err := json.Unmarshal([]byte(tt.json), &d)
+		if tt.ok && err != nil {
+			t.Errorf("Unmarshal(%s): unexpected error: %v", tt.json, err)
+			continue
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("Unmarshal(%s): expected an error", tt.json)
+			continue
+		}
+		if tt.ok && d.Duration() != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.json, d.Duration(), tt.want)
+		}
+	}
+}
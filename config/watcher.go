@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/rjeczalik/notify"
+
+	"github.com/RandomByte/onion-bt-wardriving/internal/log"
+)
+
+// Watcher keeps a Config loaded from a JSON file on disk, atomically
+// swapping in new values whenever the file is rewritten. Reloads that
+// fail to parse or fail Validate are logged and discarded, leaving the
+// previous config in place.
+type Watcher struct {
+	path    string
+	current atomic.Value // Config
+}
+
+// Watch loads path (creating it with Default's values if it doesn't
+// exist yet) and starts watching it for writes.
+func Watch(path string) (*Watcher, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := writeDefault(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %v", path, err)
+	}
+
+	w := &Watcher{path: path}
+	w.current.Store(cfg)
+
+	// Watch the containing directory rather than path itself: editors
+	// and `mv` replace a file with a rename, which stops delivery of
+	// further events on the old inode. The directory keeps seeing
+	// events across the rename; run filters them down to path's name.
+	// Buffered deeper than a single-file watch needs: the directory may
+	// hold other frequently-rewritten files (e.g. the sync cursor), and
+	// notify drops events it can't enqueue rather than blocking.
+	dir := filepath.Dir(path)
+	events := make(chan notify.EventInfo, 16)
+	if err := notify.Watch(dir, events, notify.InCloseWrite, notify.Write, notify.Create, notify.Rename); err != nil {
+		return nil, fmt.Errorf("config: watching %s: %v", dir, err)
+	}
+
+	go w.run(events)
+
+	return w, nil
+}
+
+// Current returns the most recently loaded, validated config.
+func (w *Watcher) Current() Config {
+	return w.current.Load().(Config)
+}
+
+func (w *Watcher) run(events chan notify.EventInfo) {
+	name := filepath.Base(w.path)
+
+	for ev := range events {
+		if filepath.Base(ev.Path()) != name {
+			continue
+		}
+
+		cfg, err := load(w.path)
+		if err != nil {
+			log.L.Warnf("config: reloading %s: %v", w.path, err)
+			continue
+		}
+
+		if err := cfg.Validate(); err != nil {
+			log.L.Warnf("config: rejecting reload of %s: %v", w.path, err)
+			continue
+		}
+
+		w.current.Store(cfg)
+		log.L.Infof("config: reloaded %s", w.path)
+	}
+}
+
+func load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: reading %s: %v", path, err)
+	}
+
+	cfg := Default()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("config: parsing %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+func writeDefault(path string) error {
+	data, err := json.MarshalIndent(Default(), "", "\t")
+	if err != nil {
+		return fmt.Errorf("config: encoding defaults: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("config: writing %s: %v", path, err)
+	}
+
+	return nil
+}
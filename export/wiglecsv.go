@@ -0,0 +1,73 @@
+// Package export writes observed devices out in formats understood by
+// third-party wardriving databases.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+)
+
+// wigleCSVHeader is the column layout Kismet's .wiglecsv export uses,
+// which is what wigle.net and similar databases expect on upload.
+var wigleCSVHeader = []string{
+	"MAC", "SSID", "AuthMode", "FirstSeen", "Channel", "RSSI",
+	"CurrentLatitude", "CurrentLongitude", "AltitudeMeters", "AccuracyMeters", "Type",
+}
+
+// WriteWigleCSV writes observations to w in Kismet's wiglecsv format.
+// Observations with no GPS fix are still included, with empty
+// latitude/longitude/altitude fields, rather than dropped. Fields are
+// written through encoding/csv so a device name containing a comma,
+// quote or newline (common for BT names) can't corrupt the row.
+func WriteWigleCSV(w io.Writer, observations []persistence.Observation) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(wigleCSVHeader); err != nil {
+		return err
+	}
+
+	for _, obs := range observations {
+		if err := cw.Write(wigleCSVRow(obs)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// hdopUEREMeters converts a GPS fix's HDOP into an approximate accuracy
+// radius in meters, using the civilian GPS user-equivalent-range-error
+// Kismet itself assumes for wiglecsv accuracy estimates.
+const hdopUEREMeters = 5.0
+
+func wigleCSVRow(obs persistence.Observation) []string {
+	device := obs.Device
+
+	lat, lon, alt, accuracy := "", "", "", ""
+	if !device.NoFix {
+		lat = fmt.Sprintf("%f", device.Lat)
+		lon = fmt.Sprintf("%f", device.Lon)
+		alt = fmt.Sprintf("%f", device.Alt)
+		accuracy = fmt.Sprintf("%f", device.HDOP*hdopUEREMeters)
+	}
+
+	return []string{
+		obs.MAC,
+		device.Name,
+		"[BT]",
+		time.Unix(device.FirstSeen, 0).UTC().Format("2006-01-02 15:04:05"),
+		"",
+		strconv.Itoa(int(device.RSSI)),
+		lat,
+		lon,
+		alt,
+		accuracy,
+		"BT",
+	}
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+)
+
+func TestWriteWigleCSV(t *testing.T) {
+	observations := []persistence.Observation{
+		{
+			MAC: "aa:bb:cc:dd:ee:ff",
+			Device: persistence.Device{
+				Name:      "some-device",
+				RSSI:      -42,
+				FirstSeen: 1000,
+				LastSeen:  2000,
+				Lat:       48.1173,
+				Lon:       11.5167,
+				Alt:       545.4,
+				HDOP:      0.9,
+				NoFix:     false,
+			},
+		},
+		{
+			MAC: "11:22:33:44:55:66",
+			Device: persistence.Device{
+				Name:      "no-fix-device",
+				FirstSeen: 3000,
+				LastSeen:  3000,
+				NoFix:     true,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWigleCSV(&buf, observations); err != nil {
+		t.Fatalf("WriteWigleCSV: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing written CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3 (header + 2 devices)", len(rows))
+	}
+	if strings.Join(rows[0], ",") != strings.Join(wigleCSVHeader, ",") {
+		t.Errorf("header = %v, want %v", rows[0], wigleCSVHeader)
+	}
+
+	first := rows[1]
+	if wantFirstSeen := "1970-01-01 00:16:40"; first[3] != wantFirstSeen {
+		t.Errorf("FirstSeen = %q, want %q", first[3], wantFirstSeen)
+	}
+	if wantAccuracy := "4.500000"; first[9] != wantAccuracy {
+		t.Errorf("AccuracyMeters = %q, want %q", first[9], wantAccuracy)
+	}
+
+	second := rows[2]
+	if second[6] != "" || second[7] != "" || second[8] != "" || second[9] != "" {
+		t.Errorf("expected empty lat/lon/alt/accuracy for a no-fix device, got %v", second[6:10])
+	}
+}
+
+// TestWriteWigleCSVQuotesNameField guards against a device name
+// containing a comma, quote or newline (all common in BT names)
+// corrupting the row - each must round-trip through a CSV reader as a
+// single field rather than shifting or splitting columns.
+func TestWriteWigleCSVQuotesNameField(t *testing.T) {
+	tests := []string{
+		`Living Room, TV`,
+		`Bob"s Phone`,
+		"multi\nline",
+	}
+
+	for _, name := range tests {
+		observations := []persistence.Observation{
+			{MAC: "aa:bb:cc:dd:ee:ff", Device: persistence.Device{Name: name, NoFix: true}},
+		}
+
+		var buf bytes.Buffer
+		if err := WriteWigleCSV(&buf, observations); err != nil {
+			t.Fatalf("WriteWigleCSV(%q): %v", name, err)
+		}
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("parsing written CSV for name %q: %v", name, err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("name %q: got %d rows, want 2 (header + 1 device)", name, len(rows))
+		}
+		if rows[1][1] != name {
+			t.Errorf("SSID = %q, want %q", rows[1][1], name)
+		}
+		if len(rows[1]) != len(wigleCSVHeader) {
+			t.Errorf("name %q: row has %d columns, want %d", name, len(rows[1]), len(wigleCSVHeader))
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package gps maintains the wardriver's last known position by reading
+// NMEA sentences off a serial GPS receiver, so sightings can be
+// geotagged for export to wardriving databases.
+package gps
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+)
+
+// Fix is the most recently decoded position.
+type Fix struct {
+	Lat       float64
+	Lon       float64
+	Alt       float64
+	HDOP      float64
+	Timestamp time.Time
+}
+
+// Receiver reads NMEA sentences from a serial GPS module and keeps
+// track of the last fix seen. It's safe for concurrent use.
+type Receiver struct {
+	port io.ReadWriteCloser
+
+	mu      sync.RWMutex
+	fix     Fix
+	haveFix bool
+}
+
+// Open opens the serial port at device (e.g. "/dev/ttyUSB0") at baud and
+// starts decoding $GPGGA/$GPRMC sentences in the background.
+func Open(device string, baud uint) (*Receiver, error) {
+	options := serial.OpenOptions{
+		PortName:        device,
+		BaudRate:        baud,
+		DataBits:        8,
+		StopBits:        1,
+		MinimumReadSize: 1,
+	}
+
+	port, err := serial.Open(options)
+	if err != nil {
+		return nil, fmt.Errorf("gps: opening %s: %v", device, err)
+	}
+
+	r := &Receiver{port: port}
+	go r.run()
+
+	return r, nil
+}
+
+func (r *Receiver) run() {
+	scanner := bufio.NewScanner(r.port)
+
+	for scanner.Scan() {
+		fix, ok := parseSentence(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		r.setFix(fix)
+	}
+}
+
+// LastFix returns the most recently decoded position and whether a fix
+// has been obtained yet.
+func (r *Receiver) LastFix() (Fix, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.fix, r.haveFix
+}
+
+// Close closes the underlying serial port.
+func (r *Receiver) Close() error {
+	return r.port.Close()
+}
+
+func (r *Receiver) setFix(fix Fix) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// RMC carries no altitude or HDOP, so parseRMC leaves them zero;
+	// carry the last GGA-reported values forward instead of losing them
+	// on every other sentence at a typical 1Hz GGA/RMC interleave.
+	if fix.Alt == 0 && fix.HDOP == 0 {
+		fix.Alt = r.fix.Alt
+		fix.HDOP = r.fix.HDOP
+	}
+
+	r.fix = fix
+	r.haveFix = true
+}
+
+// parseSentence decodes a single NMEA sentence, returning the fix it
+// carries and whether the sentence was a recognized, fixed GGA/RMC
+// sentence.
+func parseSentence(line string) (Fix, bool) {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexByte(line, '*'); i != -1 {
+		line = line[:i]
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields) == 0 {
+		return Fix{}, false
+	}
+
+	switch {
+	case strings.HasSuffix(fields[0], "GGA"):
+		return parseGGA(fields)
+	case strings.HasSuffix(fields[0], "RMC"):
+		return parseRMC(fields)
+	default:
+		return Fix{}, false
+	}
+}
+
+// parseGGA decodes a $GPGGA/$GNGGA sentence:
+// $GPGGA,time,lat,N/S,lon,E/W,fixQuality,numSats,hdop,alt,M,...
+func parseGGA(fields []string) (Fix, bool) {
+	if len(fields) < 10 || fields[6] == "0" {
+		return Fix{}, false
+	}
+
+	lat, ok := parseCoordinate(fields[2], fields[3])
+	if !ok {
+		return Fix{}, false
+	}
+	lon, ok := parseCoordinate(fields[4], fields[5])
+	if !ok {
+		return Fix{}, false
+	}
+
+	hdop, _ := strconv.ParseFloat(fields[8], 64)
+	alt, _ := strconv.ParseFloat(fields[9], 64)
+
+	return Fix{Lat: lat, Lon: lon, Alt: alt, HDOP: hdop, Timestamp: time.Now()}, true
+}
+
+// parseRMC decodes a $GPRMC/$GNRMC sentence:
+// $GPRMC,time,status,lat,N/S,lon,E/W,speed,course,date,...
+func parseRMC(fields []string) (Fix, bool) {
+	if len(fields) < 7 || fields[2] != "A" {
+		return Fix{}, false
+	}
+
+	lat, ok := parseCoordinate(fields[3], fields[4])
+	if !ok {
+		return Fix{}, false
+	}
+	lon, ok := parseCoordinate(fields[5], fields[6])
+	if !ok {
+		return Fix{}, false
+	}
+
+	return Fix{Lat: lat, Lon: lon, Timestamp: time.Now()}, true
+}
+
+// parseCoordinate decodes an NMEA ddmm.mmmm/dddmm.mmmm coordinate plus
+// its hemisphere letter into signed decimal degrees.
+func parseCoordinate(raw, hemisphere string) (float64, bool) {
+	if raw == "" {
+		return 0, false
+	}
+
+	dot := strings.IndexByte(raw, '.')
+	if dot < 2 {
+		return 0, false
+	}
+
+	degrees, err := strconv.ParseFloat(raw[:dot-2], 64)
+	if err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.ParseFloat(raw[dot-2:], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	decimal := degrees + minutes/60
+	if hemisphere == "S" || hemisphere == "W" {
+		decimal = -decimal
+	}
+
+	return decimal, true
+}
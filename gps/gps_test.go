@@ -0,0 +1,101 @@
+package gps
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		raw        string
+		hemisphere string
+		want       float64
+		ok         bool
+	}{
+		{"4807.038", "N", 48 + 7.038/60, true},
+		{"4807.038", "S", -(48 + 7.038/60), true},
+		{"01131.000", "E", 11 + 31.0/60, true},
+		{"01131.000", "W", -(11 + 31.0/60), true},
+		{"", "N", 0, false},
+		{"4.038", "N", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseCoordinate(tt.raw, tt.hemisphere)
+		if ok != tt.ok {
+			t.Errorf("parseCoordinate(%q, %q) ok = %v, want %v", tt.raw, tt.hemisphere, ok, tt.ok)
+			continue
+		}
+		if ok && math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("parseCoordinate(%q, %q) = %v, want %v", tt.raw, tt.hemisphere, got, tt.want)
+		}
+	}
+}
+
+func TestParseGGA(t *testing.T) {
+	fields := []string{"$GPGGA", "123519", "4807.038", "N", "01131.000", "E", "1", "08", "0.9", "545.4", "M", "46.9", "M", "", ""}
+
+	fix, ok := parseGGA(fields)
+	if !ok {
+		t.Fatal("parseGGA: expected ok")
+	}
+	if math.Abs(fix.Lat-(48+7.038/60)) > 1e-6 {
+		t.Errorf("Lat = %v, want ~48.1173", fix.Lat)
+	}
+	if math.Abs(fix.Lon-(11+31.0/60)) > 1e-6 {
+		t.Errorf("Lon = %v, want ~11.5167", fix.Lon)
+	}
+	if fix.Alt != 545.4 {
+		t.Errorf("Alt = %v, want 545.4", fix.Alt)
+	}
+	if fix.HDOP != 0.9 {
+		t.Errorf("HDOP = %v, want 0.9", fix.HDOP)
+	}
+
+	fields[6] = "0"
+	if _, ok := parseGGA(fields); ok {
+		t.Error("parseGGA: expected not ok for fix quality 0")
+	}
+}
+
+func TestParseRMC(t *testing.T) {
+	fields := []string{"$GPRMC", "123519", "A", "4807.038", "N", "01131.000", "E", "022.4", "084.4", "230394", "003.1", "W"}
+
+	fix, ok := parseRMC(fields)
+	if !ok {
+		t.Fatal("parseRMC: expected ok")
+	}
+	if math.Abs(fix.Lat-(48+7.038/60)) > 1e-6 {
+		t.Errorf("Lat = %v, want ~48.1173", fix.Lat)
+	}
+
+	fields[2] = "V"
+	if _, ok := parseRMC(fields); ok {
+		t.Error("parseRMC: expected not ok for void status")
+	}
+}
+
+// TestReceiverSetFixCarriesAltHDOPAcrossRMC guards against a receiver
+// interleaving GGA and RMC sentences (typical at 1Hz) losing the last
+// known altitude/HDOP every time an RMC sentence comes in, since RMC
+// carries neither.
+func TestReceiverSetFixCarriesAltHDOPAcrossRMC(t *testing.T) {
+	r := &Receiver{}
+
+	r.setFix(Fix{Lat: 1, Lon: 2, Alt: 545.4, HDOP: 0.9})
+	r.setFix(Fix{Lat: 1.001, Lon: 2.001}) // as parseRMC would produce
+
+	fix, ok := r.LastFix()
+	if !ok {
+		t.Fatal("LastFix: expected ok")
+	}
+	if fix.Alt != 545.4 {
+		t.Errorf("Alt = %v, want 545.4 carried forward from the last GGA", fix.Alt)
+	}
+	if fix.HDOP != 0.9 {
+		t.Errorf("HDOP = %v, want 0.9 carried forward from the last GGA", fix.HDOP)
+	}
+	if fix.Lat != 1.001 || fix.Lon != 2.001 {
+		t.Errorf("Lat/Lon = %v/%v, want the RMC-reported position to still be applied", fix.Lat, fix.Lon)
+	}
+}
@@ -0,0 +1,117 @@
+// Package log is a small leveled logger with per-category debug gating,
+// so noisy paths (scan, persist, oled, ...) can be silenced or enabled
+// at runtime without a rebuild.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger writes leveled, optionally JSON-formatted log lines. The zero
+// value is not usable; use New or the package-level L.
+type Logger struct {
+	mu       sync.Mutex
+	out      io.Writer
+	json     bool
+	trace    map[string]bool
+	traceAll bool
+}
+
+// L is the package-level logger used throughout the wardriver.
+var L = New()
+
+// New creates a Logger writing to stdout, with its debug categories
+// taken from the OBTW_TRACE environment variable (e.g.
+// "OBTW_TRACE=scan,persist,oled" or "OBTW_TRACE=all"), in the style of
+// syncthing's STTRACE.
+func New() *Logger {
+	trace, traceAll := parseTrace(os.Getenv("OBTW_TRACE"))
+
+	return &Logger{
+		out:      os.Stdout,
+		trace:    trace,
+		traceAll: traceAll,
+	}
+}
+
+func parseTrace(raw string) (map[string]bool, bool) {
+	trace := make(map[string]bool)
+
+	for _, cat := range strings.Split(raw, ",") {
+		cat = strings.TrimSpace(cat)
+		if cat == "" {
+			continue
+		}
+		if cat == "all" {
+			return trace, true
+		}
+		trace[cat] = true
+	}
+
+	return trace, false
+}
+
+// SetJSON switches l to emit JSON-formatted lines, suitable for
+// shipping off-device, instead of plain text.
+func (l *Logger) SetJSON(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.json = enabled
+}
+
+// Infof logs an informational message. Always emitted.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf("info", "", format, args...)
+}
+
+// Warnf logs a warning. Always emitted.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf("warn", "", format, args...)
+}
+
+// Debugf logs a debug message tagged with category. It's only emitted
+// if category (or "all") is enabled via OBTW_TRACE.
+func (l *Logger) Debugf(category, format string, args ...interface{}) {
+	if !l.traceEnabled(category) {
+		return
+	}
+
+	l.logf("debug", category, format, args...)
+}
+
+func (l *Logger) traceEnabled(category string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.traceAll || l.trace[category]
+}
+
+func (l *Logger) logf(level, category, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.json {
+		json.NewEncoder(l.out).Encode(map[string]string{
+			"time":     time.Now().Format(time.RFC3339),
+			"level":    level,
+			"category": category,
+			"msg":      msg,
+		})
+		return
+	}
+
+	tag := strings.ToUpper(level)
+	if category != "" {
+		tag += "(" + category + ")"
+	}
+	fmt.Fprintf(l.out, "%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), tag, msg)
+}
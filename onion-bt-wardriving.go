@@ -2,33 +2,67 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/peterbourgon/diskv"
+	"github.com/RandomByte/onion-bt-wardriving/bluetooth"
+	"github.com/RandomByte/onion-bt-wardriving/config"
+	"github.com/RandomByte/onion-bt-wardriving/export"
+	"github.com/RandomByte/onion-bt-wardriving/gps"
+	"github.com/RandomByte/onion-bt-wardriving/internal/log"
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+	"github.com/RandomByte/onion-bt-wardriving/syncapi"
 	"os"
 	"os/exec"
 	"os/signal"
-	"regexp"
+	"runtime/debug"
 	"strings"
 	"syscall"
 	"time"
 )
 
-type device struct {
-	Name     string
-	Count    int
-	LastSeen int64
-}
+var l = log.L
+
+type device = persistence.Device
+
+var buffer []string
 
-var re = regexp.MustCompile("(?im)^[^0-9a-f]*((?:[0-9a-f]{2}:){5}[0-9a-f]{2})\\s*([^\\s].*)?$")
-var buffer = make([]string, 8, 8)
+var (
+	configPath     = flag.String("config", "config.json", "path to the hot-reloaded config.json")
+	dbBackend      = flag.String("db-backend", "", "persistence backend to use: diskv, sqlite or postgres (overrides config.json)")
+	dbDSN          = flag.String("db-dsn", "", "data source for the chosen backend (overrides config.json)")
+	btAdapter      = flag.String("bt-adapter", "", "BlueZ adapter to scan with (overrides config.json)")
+	gpsDevice      = flag.String("gps-device", "", "serial port of a NMEA GPS receiver to geotag sightings with, e.g. /dev/ttyUSB0 (disabled if empty)")
+	gpsBaud        = flag.Uint("gps-baud", 9600, "baud rate of the GPS serial port")
+	wigleCSVOut    = flag.String("wiglecsv-out", "", "path to write a wiglecsv export of all known devices to on exit (disabled if empty)")
+	logJSON        = flag.Bool("log-json", false, "emit JSON-formatted log lines, suitable for shipping off-device")
+	syncAddr       = flag.String("sync-addr", "", "address of a sync server to push sightings to, e.g. collector:4657 (disabled if empty)")
+	syncInterval   = flag.Duration("sync-interval", 30*time.Second, "how often to flush pending sightings to the sync server")
+	syncCursorFile = flag.String("sync-cursor-file", "sync-cursor", "path to persist the sync resume cursor across restarts (used with -sync-addr)")
+)
 
-var dv *diskv.Diskv
+var store persistence.Store
+var scanner *bluetooth.Scanner
+var gpsReceiver *gps.Receiver
+var cfgWatcher *config.Watcher
+var syncClient *syncapi.Client
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		serverMain(os.Args[2:])
+		return
+	}
+
+	flag.Parse()
+	l.SetJSON(*logJSON)
+
+	setupConfig()
 	setupPersistence()
 	setupBt()
 	setupOled()
+	setupGps()
+	setupSync()
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, os.Kill, syscall.SIGTERM)
@@ -36,36 +70,49 @@ func main() {
 
 	for {
 		select {
-		case <-time.After(1 * time.Millisecond):
-			loop()
+		case obs, ok := <-scanner.Discoveries():
+			if !ok {
+				l.Warnf("Scanner stopped, quitting...")
+				exportWigleCSV()
+				return
+			}
+			loop(obs)
 		case s := <-sig:
-			fmt.Println("Got signal:", s)
-			fmt.Println("Quitting...")
+			l.Infof("Got signal: %v", s)
+			l.Infof("Quitting...")
+			exportWigleCSV()
 			return
 		}
 	}
 }
 
-func loop() {
+func loop(obs bluetooth.Observation) {
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("Recovered", r)
+			l.Warnf("Recovered: %v\n%s", r, debug.Stack())
 		}
 	}()
-	result := scan()
-	parsed := parse(result)
-	var somethingHappend bool
 
-	for mac, device := range parsed {
-		knownDevice := readDevice(mac)
-		if knownDevice == nil {
-			handleNewDevice(mac, device)
+	mac := obs.MAC
+	if !cfgWatcher.Current().Allows(mac) {
+		return
+	}
+
+	var somethingHappend bool
+	knownDevice, err := store.GetDevice(mac)
+	switch {
+	case errors.Is(err, persistence.ErrNotFound):
+		device := deviceFromObservation(obs, mac)
+		handleNewDevice(mac, device)
+		somethingHappend = true
+	case err != nil:
+		l.Warnf("persist: looking up %s: %v", mac, err)
+		return
+	default:
+		device := deviceFromObservation(obs, knownDevice.Name)
+		ignored := handleKnownDevice(mac, device, *knownDevice)
+		if ignored != true {
 			somethingHappend = true
-		} else {
-			ignored := handleKnownDevice(mac, device, *knownDevice)
-			if ignored != true {
-				somethingHappend = true
-			}
 		}
 	}
 
@@ -76,104 +123,228 @@ func loop() {
 	}
 }
 
+// deviceFromObservation builds a device record from a raw observation.
+// fallbackName is used when obs carries no name of its own - a
+// PropertiesChanged update (e.g. an RSSI-only change) has none, so the
+// caller passes the known device's name to avoid overwriting it; for a
+// never-before-seen MAC it passes the MAC itself.
+func deviceFromObservation(obs bluetooth.Observation, fallbackName string) device {
+	name := obs.Name
+	if name == "" {
+		name = fallbackName
+	}
+
+	d := device{
+		Name:             name,
+		LastSeen:         obs.Timestamp.Unix(),
+		RSSI:             obs.RSSI,
+		TxPower:          obs.TxPower,
+		AddressType:      obs.AddressType,
+		ServiceUUIDs:     obs.ServiceUUIDs,
+		ManufacturerData: obs.ManufacturerData,
+		NoFix:            true,
+	}
+
+	if gpsReceiver != nil {
+		if fix, ok := gpsReceiver.LastFix(); ok {
+			d.Lat = fix.Lat
+			d.Lon = fix.Lon
+			d.Alt = fix.Alt
+			d.FixTime = fix.Timestamp.Unix()
+			d.HDOP = fix.HDOP
+			d.NoFix = false
+		}
+	}
+
+	return d
+}
+
 func handleNewDevice(mac string, device device) {
-	fmt.Printf("New device %s: %s\n", device.Name, mac)
+	device.FirstSeen = device.LastSeen
+
+	l.Debugf("scan", "New device %s: %s", device.Name, mac)
 	writeOled(device)
 	persist(mac, device)
 }
 
 func handleKnownDevice(mac string, device device, knownDevice device) bool {
-	if time.Since(time.Unix(knownDevice.LastSeen, 0)).Hours() < 5 {
-		// Last seen less then five hours ago
+	if time.Since(time.Unix(knownDevice.LastSeen, 0)) < cfgWatcher.Current().CooldownWindow.Duration() {
+		// Still within the cooldown window
 		return true
 	}
 
+	device.FirstSeen = knownDevice.FirstSeen
+
 	if device.Name != knownDevice.Name {
-		fmt.Printf("Same MAC but different name: %s (new) vs. %s (known)\n", device.Name, knownDevice.Name)
+		l.Infof("Same MAC but different name: %s (new) vs. %s (known)", device.Name, knownDevice.Name)
 
-		err := dv.Write("nameclash"+mac+string(time.Now().Unix()), []byte(fmt.Sprintf("%s, %s (new) vs. %s (known)", mac, device.Name, knownDevice.Name)))
+		err := store.RecordNameClash(mac, knownDevice.Name, device.Name)
 		if err != nil {
-			fmt.Println(err)
+			l.Warnf("persist: recording name clash: %v", err)
 		}
 	}
 
 	device.Count = knownDevice.Count + 1
-	fmt.Printf("%vx Known device %s: %s\n", device.Count, device.Name, mac)
+	l.Debugf("scan", "%vx Known device %s: %s", device.Count, device.Name, mac)
 	writeOled(device)
 	persist(mac, device)
 
 	return false
 }
 
-func scan() string {
-	// Create an *exec.Cmd
-	cmd := exec.Command("hcitool", "scan", "--flush")
+func setupConfig() {
+	w, err := config.Watch(*configPath)
+	if err != nil {
+		panic(err)
+	}
 
-	// Stdout buffer
-	cmdOutput := &bytes.Buffer{}
-	// Attach buffer to command
-	cmd.Stdout = cmdOutput
+	cfgWatcher = w
+}
+
+func setupPersistence() {
+	backend := *dbBackend
+	if backend == "" {
+		backend = cfgWatcher.Current().DBBackend
+	}
+	dsn := *dbDSN
+	if dsn == "" {
+		dsn = cfgWatcher.Current().DBDSN
+	}
+
+	s, err := persistence.Open(backend, dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	store = s
+}
+
+func persist(mac string, device device) {
+	err := store.PutDevice(mac, device)
+	if err != nil {
+		panic(err)
+	}
+
+	if syncClient != nil {
+		syncClient.Enqueue(mac, device)
+	}
+}
+
+func setupBt() {
+	adapter := *btAdapter
+	if adapter == "" {
+		adapter = cfgWatcher.Current().ScanBackend
+	}
+
+	cmd := exec.Command("hciconfig", adapter, "up")
 
 	err := cmd.Run() // will wait for command to return
 	if err != nil {
-		fmt.Println(err)
+		l.Warnf("scan: bringing up %s: %v", adapter, err)
+	}
+
+	s, err := bluetooth.NewScanner(adapter)
+	if err != nil {
 		panic(err)
 	}
-	return cmdOutput.String()
+	scanner = s
 }
 
-func parse(rawScanResult string) map[string]device {
-	matches := re.FindAllStringSubmatch(rawScanResult, -1)
-	devices := make(map[string]device)
-	for _, match := range matches {
-		name := match[2]
-		if name == "" {
-			name = match[1]
-		}
-		devices[match[1]] = device{Name: name, LastSeen: time.Now().Unix()}
+func setupGps() {
+	if *gpsDevice == "" {
+		return
 	}
 
-	return devices
+	r, err := gps.Open(*gpsDevice, *gpsBaud)
+	if err != nil {
+		l.Warnf("gps: %v", err)
+		return
+	}
+	gpsReceiver = r
 }
 
-func readDevice(mac string) *device {
-	value, err := dv.Read(mac)
+func setupSync() {
+	if *syncAddr == "" {
+		return
+	}
+
+	c, err := syncapi.Dial(*syncAddr)
 	if err != nil {
-		return nil
+		l.Warnf("syncapi: %v", err)
+		return
 	}
+	syncClient = c
 
-	res := &device{}
-	json.Unmarshal([]byte(value), res)
+	// Resume in the background: a collector that's unreachable or has a
+	// large backlog to stream shouldn't block the scan loop from
+	// starting.
+	go resumeSync(syncClient)
 
-	return res
+	go syncClient.Run(context.Background(), *syncInterval)
 }
 
-func setupPersistence() {
-	// Simplest transform function: put all the data files into the base dir.
-	flatTransform := func(s string) []string { return []string{} }
+func resumeSync(c *syncapi.Client) {
+	cursor := loadSyncCursor(*syncCursorFile)
 
-	// Initialize a new diskv store, rooted at "diskv-data", with a 1MB cache.
-	dv = diskv.New(diskv.Options{
-		BasePath:     "diskv-data",
-		Transform:    flatTransform,
-		CacheSizeMax: 1024 * 1024,
-	})
+	onAdvance := func(cursor time.Time) {
+		if err := saveSyncCursor(*syncCursorFile, cursor); err != nil {
+			l.Warnf("syncapi: persisting sync cursor: %v", err)
+		}
+	}
+
+	if _, err := c.Resume(context.Background(), cursor, store, onAdvance); err != nil {
+		l.Warnf("syncapi: resuming from %s: %v", *syncCursorFile, err)
+	}
 }
 
-func persist(mac string, device device) {
-	serialized, _ := json.Marshal(device)
-	err := dv.Write(mac, []byte(serialized))
+// loadSyncCursor reads the resume cursor left by a previous run, so a
+// node that was offline picks up Subscribe where it left off instead of
+// re-receiving every sighting the collector has. A missing or corrupt
+// file just means a full resync, which is always safe.
+func loadSyncCursor(path string) time.Time {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return time.Time{}
 	}
+
+	cursor, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		l.Warnf("syncapi: parsing cursor in %s: %v", path, err)
+		return time.Time{}
+	}
+
+	return cursor
 }
 
-func setupBt() {
-	cmd := exec.Command("hciconfig", "hci0", "up")
+func saveSyncCursor(path string, cursor time.Time) error {
+	return os.WriteFile(path, []byte(cursor.Format(time.RFC3339Nano)), 0644)
+}
 
-	err := cmd.Run() // will wait for command to return
+func exportWigleCSV() {
+	if *wigleCSVOut == "" {
+		return
+	}
+
+	observations, err := store.IterateSince(0)
+	if err != nil {
+		l.Warnf("persist: exporting wiglecsv: %v", err)
+		return
+	}
+
+	f, err := os.Create(*wigleCSVOut)
 	if err != nil {
-		fmt.Println(err)
+		l.Warnf("persist: exporting wiglecsv: %v", err)
+		return
+	}
+	defer f.Close()
+
+	var all []persistence.Observation
+	for obs := range observations {
+		all = append(all, obs)
+	}
+
+	if err := export.WriteWigleCSV(f, all); err != nil {
+		l.Warnf("persist: exporting wiglecsv: %v", err)
 	}
 }
 
@@ -182,15 +353,18 @@ func setupOled() {
 
 	err := cmd.Run() // will wait for command to return
 	if err != nil {
-		fmt.Println(err)
+		l.Warnf("oled: initializing: %v", err)
 	}
 }
 
 func writeOled(device device) {
 	msg := fmt.Sprintf("%s (%vx)", device.Name, device.Count)
 
-	_, buffer = buffer[len(buffer)-1], buffer[:len(buffer)-1]
 	buffer = append([]string{msg}, buffer...)
+
+	if lines := cfgWatcher.Current().OledLines; len(buffer) > lines {
+		buffer = buffer[:lines]
+	}
 }
 
 func getOledMsg() string {
@@ -201,7 +375,7 @@ func flushOled() {
 	cmd := exec.Command("/bin/sh", "write-oled.sh", "\""+getOledMsg()+"\"")
 
 	// cmd := exec.Command("/usr/sbin/oled-exp", "cursor 0,0 write stf")
-	fmt.Printf("==> Executing: %s\n", strings.Join(cmd.Args, " "))
+	l.Debugf("oled", "==> Executing: %s", strings.Join(cmd.Args, " "))
 
 	// Stdout buffer
 	cmdOutput := &bytes.Buffer{}
@@ -210,22 +384,18 @@ func flushOled() {
 
 	err := cmd.Run() // will wait for command to return
 	if err != nil {
-		fmt.Println(err)
+		l.Warnf("oled: %v", err)
 	}
-	fmt.Printf("==> Output: %s\n", string(cmdOutput.Bytes()))
+	l.Debugf("oled", "==> Output: %s", string(cmdOutput.Bytes()))
 }
 
 func notify() {
-	cmdBlue := exec.Command("expled", "0x0000ff")
-
-	err := cmdBlue.Run() // will wait for command to return
-	if err != nil {
-		fmt.Println(err)
-	}
+	for _, step := range cfgWatcher.Current().NotifySequence {
+		cmd := exec.Command("expled", step.Color)
+		if err := cmd.Run(); err != nil {
+			l.Warnf("notify: %v", err)
+		}
 
-	cmdOff := exec.Command("expled", "0x000000")
-	err = cmdOff.Run() // will wait for command to return
-	if err != nil {
-		fmt.Println(err)
+		time.Sleep(step.Duration.Duration())
 	}
 }
\ No newline at end of file
@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/diskv"
+)
+
+const nameClashPrefix = "nameclash"
+
+// DiskvStore is the original, single-node persistence backend: devices are
+// JSON-encoded and kept as individual files on disk via diskv.
+type DiskvStore struct {
+	dv *diskv.Diskv
+}
+
+// NewDiskvStore opens (creating if necessary) a diskv-backed store rooted
+// at basePath.
+func NewDiskvStore(basePath string) (*DiskvStore, error) {
+	flatTransform := func(s string) []string { return []string{} }
+
+	dv := diskv.New(diskv.Options{
+		BasePath:     basePath,
+		Transform:    flatTransform,
+		CacheSizeMax: 1024 * 1024,
+	})
+
+	return &DiskvStore{dv: dv}, nil
+}
+
+func (s *DiskvStore) GetDevice(mac string) (*Device, error) {
+	value, err := s.dv.Read(mac)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	device := &Device{}
+	if err := json.Unmarshal(value, device); err != nil {
+		return nil, fmt.Errorf("persistence: decoding %s: %v", mac, err)
+	}
+
+	return device, nil
+}
+
+func (s *DiskvStore) PutDevice(mac string, device Device) error {
+	serialized, err := json.Marshal(device)
+	if err != nil {
+		return fmt.Errorf("persistence: encoding %s: %v", mac, err)
+	}
+
+	return s.dv.Write(mac, serialized)
+}
+
+func (s *DiskvStore) RecordNameClash(mac, oldName, newName string) error {
+	key := fmt.Sprintf("%s%s%d", nameClashPrefix, mac, time.Now().Unix())
+	value := fmt.Sprintf("%s, %s (new) vs. %s (known)", mac, newName, oldName)
+
+	return s.dv.Write(key, []byte(value))
+}
+
+func (s *DiskvStore) IterateSince(t int64) (<-chan Observation, error) {
+	out := make(chan Observation)
+
+	go func() {
+		defer close(out)
+
+		for mac := range s.dv.Keys(nil) {
+			if strings.HasPrefix(mac, nameClashPrefix) {
+				continue
+			}
+
+			device, err := s.GetDevice(mac)
+			if err != nil {
+				continue
+			}
+
+			if device.LastSeen >= t {
+				out <- Observation{MAC: mac, Device: *device}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *DiskvStore) Close() error {
+	return nil
+}
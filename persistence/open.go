@@ -0,0 +1,24 @@
+package persistence
+
+import "fmt"
+
+// Open opens the Store identified by backend, using dsn as appropriate:
+//
+//   - "diskv" (the default): dsn is a base directory, e.g. "diskv-data".
+//   - "sqlite": dsn is a path to a SQLite database file.
+//   - "postgres": dsn is a PostgreSQL connection string.
+func Open(backend, dsn string) (Store, error) {
+	switch backend {
+	case "", "diskv":
+		if dsn == "" {
+			dsn = "diskv-data"
+		}
+		return NewDiskvStore(dsn)
+	case "sqlite":
+		return NewSQLiteStore(dsn)
+	case "postgres":
+		return NewPostgresStore(dsn)
+	default:
+		return nil, fmt.Errorf("persistence: unknown backend %q", backend)
+	}
+}
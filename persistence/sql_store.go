@@ -0,0 +1,224 @@
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// sqlStore is shared by the SQLite and PostgreSQL backends: both speak
+// database/sql and only differ in driver name, DSN and placeholder
+// style, which is why they're kept as thin wrappers around this type
+// rather than duplicated.
+type sqlStore struct {
+	db *sql.DB
+
+	// placeholder formats the i'th (1-based) bind parameter for the
+	// underlying driver, e.g. "?" for sqlite3 and "$1" for lib/pq.
+	placeholder func(i int) string
+}
+
+func openSQLStore(driverName, dsn string, placeholder func(i int) string) (*sqlStore, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: opening %s: %v", driverName, err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("persistence: connecting to %s: %v", driverName, err)
+	}
+
+	s := &sqlStore{db: db, placeholder: placeholder}
+	if err := s.createSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sqlStore) createSchema() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS devices (
+			mac TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			count INTEGER NOT NULL,
+			last_seen INTEGER NOT NULL,
+			rssi INTEGER NOT NULL DEFAULT 0,
+			tx_power INTEGER NOT NULL DEFAULT 0,
+			address_type TEXT NOT NULL DEFAULT '',
+			service_uuids TEXT NOT NULL DEFAULT '[]',
+			manufacturer_data TEXT NOT NULL DEFAULT '{}',
+			lat DOUBLE PRECISION NOT NULL DEFAULT 0,
+			lon DOUBLE PRECISION NOT NULL DEFAULT 0,
+			alt DOUBLE PRECISION NOT NULL DEFAULT 0,
+			fix_time INTEGER NOT NULL DEFAULT 0,
+			hdop DOUBLE PRECISION NOT NULL DEFAULT 0,
+			no_fix BOOLEAN NOT NULL DEFAULT TRUE,
+			first_seen INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS name_clashes (
+			mac TEXT NOT NULL,
+			old_name TEXT NOT NULL,
+			new_name TEXT NOT NULL,
+			seen_at INTEGER NOT NULL
+		)`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("persistence: creating schema: %v", err)
+		}
+	}
+
+	// CREATE TABLE IF NOT EXISTS is a no-op against a devices table from
+	// before hdop/first_seen existed, so add them explicitly too; the
+	// ALTER fails harmlessly with "duplicate column" on a table that
+	// already has them.
+	for _, column := range []string{
+		`ALTER TABLE devices ADD COLUMN hdop DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE devices ADD COLUMN first_seen INTEGER NOT NULL DEFAULT 0`,
+	} {
+		s.db.Exec(column)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) p(i int) string {
+	return s.placeholder(i)
+}
+
+func (s *sqlStore) GetDevice(mac string) (*Device, error) {
+	query := fmt.Sprintf(
+		`SELECT name, count, last_seen, rssi, tx_power, address_type, service_uuids, manufacturer_data,
+			lat, lon, alt, fix_time, hdop, no_fix, first_seen FROM devices WHERE mac = %s`,
+		s.p(1))
+
+	var serviceUUIDs, manufacturerData string
+	device := &Device{}
+	row := s.db.QueryRow(query, mac)
+	err := row.Scan(&device.Name, &device.Count, &device.LastSeen, &device.RSSI, &device.TxPower,
+		&device.AddressType, &serviceUUIDs, &manufacturerData,
+		&device.Lat, &device.Lon, &device.Alt, &device.FixTime, &device.HDOP, &device.NoFix, &device.FirstSeen)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("persistence: reading %s: %v", mac, err)
+	}
+
+	if err := unmarshalDeviceExtras(device, serviceUUIDs, manufacturerData); err != nil {
+		return nil, fmt.Errorf("persistence: decoding %s: %v", mac, err)
+	}
+
+	return device, nil
+}
+
+func (s *sqlStore) PutDevice(mac string, device Device) error {
+	serviceUUIDs, manufacturerData, err := marshalDeviceExtras(device)
+	if err != nil {
+		return fmt.Errorf("persistence: encoding %s: %v", mac, err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO devices (mac, name, count, last_seen, rssi, tx_power, address_type, service_uuids, manufacturer_data,
+			lat, lon, alt, fix_time, hdop, no_fix, first_seen)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+		ON CONFLICT (mac) DO UPDATE SET name = excluded.name, count = excluded.count, last_seen = excluded.last_seen,
+			rssi = excluded.rssi, tx_power = excluded.tx_power, address_type = excluded.address_type,
+			service_uuids = excluded.service_uuids, manufacturer_data = excluded.manufacturer_data,
+			lat = excluded.lat, lon = excluded.lon, alt = excluded.alt, fix_time = excluded.fix_time,
+			hdop = excluded.hdop, no_fix = excluded.no_fix, first_seen = excluded.first_seen`,
+		s.p(1), s.p(2), s.p(3), s.p(4), s.p(5), s.p(6), s.p(7), s.p(8), s.p(9), s.p(10), s.p(11), s.p(12), s.p(13), s.p(14), s.p(15), s.p(16))
+
+	_, err = s.db.Exec(query, mac, device.Name, device.Count, device.LastSeen, device.RSSI, device.TxPower,
+		device.AddressType, serviceUUIDs, manufacturerData,
+		device.Lat, device.Lon, device.Alt, device.FixTime, device.HDOP, device.NoFix, device.FirstSeen)
+	if err != nil {
+		return fmt.Errorf("persistence: writing %s: %v", mac, err)
+	}
+
+	return nil
+}
+
+func marshalDeviceExtras(device Device) (serviceUUIDs, manufacturerData string, err error) {
+	uuids := device.ServiceUUIDs
+	if uuids == nil {
+		uuids = []string{}
+	}
+	mfgData := device.ManufacturerData
+	if mfgData == nil {
+		mfgData = map[uint16][]byte{}
+	}
+
+	rawUUIDs, err := json.Marshal(uuids)
+	if err != nil {
+		return "", "", err
+	}
+	rawMfgData, err := json.Marshal(mfgData)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(rawUUIDs), string(rawMfgData), nil
+}
+
+func unmarshalDeviceExtras(device *Device, serviceUUIDs, manufacturerData string) error {
+	if err := json.Unmarshal([]byte(serviceUUIDs), &device.ServiceUUIDs); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(manufacturerData), &device.ManufacturerData)
+}
+
+func (s *sqlStore) RecordNameClash(mac, oldName, newName string) error {
+	query := fmt.Sprintf("INSERT INTO name_clashes (mac, old_name, new_name, seen_at) VALUES (%s, %s, %s, %s)",
+		s.p(1), s.p(2), s.p(3), s.p(4))
+
+	_, err := s.db.Exec(query, mac, oldName, newName, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("persistence: recording name clash for %s: %v", mac, err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) IterateSince(t int64) (<-chan Observation, error) {
+	query := fmt.Sprintf(
+		`SELECT mac, name, count, last_seen, rssi, tx_power, address_type, service_uuids, manufacturer_data,
+			lat, lon, alt, fix_time, hdop, no_fix, first_seen FROM devices WHERE last_seen >= %s`,
+		s.p(1))
+
+	rows, err := s.db.Query(query, t)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: iterating since %d: %v", t, err)
+	}
+
+	out := make(chan Observation)
+	go func() {
+		defer close(out)
+		defer rows.Close()
+
+		for rows.Next() {
+			var obs Observation
+			var serviceUUIDs, manufacturerData string
+			if err := rows.Scan(&obs.MAC, &obs.Device.Name, &obs.Device.Count, &obs.Device.LastSeen,
+				&obs.Device.RSSI, &obs.Device.TxPower, &obs.Device.AddressType, &serviceUUIDs, &manufacturerData,
+				&obs.Device.Lat, &obs.Device.Lon, &obs.Device.Alt, &obs.Device.FixTime, &obs.Device.HDOP,
+				&obs.Device.NoFix, &obs.Device.FirstSeen); err != nil {
+				return
+			}
+			if err := unmarshalDeviceExtras(&obs.Device, serviceUUIDs, manufacturerData); err != nil {
+				return
+			}
+			out <- obs
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
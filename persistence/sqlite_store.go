@@ -0,0 +1,23 @@
+package persistence
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore persists devices in a local SQLite database. It's a
+// drop-in replacement for DiskvStore for nodes that want SQL access to
+// their own data without standing up a central server.
+type SQLiteStore struct {
+	*sqlStore
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at
+// path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	s, err := openSQLStore("sqlite3", path, func(i int) string { return "?" })
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteStore{sqlStore: s}, nil
+}
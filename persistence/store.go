@@ -0,0 +1,69 @@
+// Package persistence defines the storage backend used by the wardriver
+// to remember which devices it has already seen.
+package persistence
+
+import "errors"
+
+// ErrNotFound is returned by Store.GetDevice when no device is known for
+// the given MAC address.
+var ErrNotFound = errors.New("persistence: device not found")
+
+// Device is a single observed device, as kept in the store.
+type Device struct {
+	Name     string
+	Count    int
+	LastSeen int64
+
+	// Populated from BlueZ discoveries; zero-valued for devices seen
+	// only through older hcitool-based scans.
+	RSSI             int16
+	TxPower          int16
+	AddressType      string
+	ServiceUUIDs     []string
+	ManufacturerData map[uint16][]byte
+
+	// Populated from the gps package at sighting time. NoFix is set
+	// when the receiver had no fix yet, so the sighting is still kept
+	// rather than dropped; Lat/Lon/Alt/FixTime/HDOP are meaningless then.
+	Lat     float64
+	Lon     float64
+	Alt     float64
+	FixTime int64
+	HDOP    float64
+	NoFix   bool
+
+	// FirstSeen is the Unix time this MAC was first observed. Unlike
+	// LastSeen it is set once, on the new-device path, and carried
+	// forward unchanged on every later sighting.
+	FirstSeen int64
+}
+
+// Observation is a (mac, device) pair yielded by Store.IterateSince.
+type Observation struct {
+	MAC    string
+	Device Device
+}
+
+// Store is implemented by the various persistence backends. A Store
+// remembers devices by MAC address and keeps a log of name clashes (the
+// same MAC showing up with a different name than before).
+type Store interface {
+	// GetDevice looks up a previously seen device. It returns
+	// ErrNotFound if the MAC is not known.
+	GetDevice(mac string) (*Device, error)
+
+	// PutDevice stores (or overwrites) the device known under mac.
+	PutDevice(mac string, device Device) error
+
+	// RecordNameClash notes that mac was seen under oldName before and
+	// is now reporting newName.
+	RecordNameClash(mac, oldName, newName string) error
+
+	// IterateSince streams every device last seen at or after t. The
+	// returned channel is closed once all matching devices have been
+	// sent.
+	IterateSince(t int64) (<-chan Observation, error)
+
+	// Close releases any resources held by the store.
+	Close() error
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+	"github.com/RandomByte/onion-bt-wardriving/syncapi"
+)
+
+// serverMain runs the "server" subcommand: a collector that a fleet of
+// wardriving nodes can push their sightings to and subscribe from, via
+// syncapi.SightingService.
+func serverMain(args []string) {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	listen := fs.String("listen", ":4657", "address to serve the SightingService gRPC API on")
+	dbBackend := fs.String("db-backend", "diskv", "persistence backend to use: diskv, sqlite or postgres")
+	dbDSN := fs.String("db-dsn", "diskv-data", "data source for the chosen backend")
+	fs.Parse(args)
+
+	store, err := persistence.Open(*dbBackend, *dbDSN)
+	if err != nil {
+		l.Warnf("server: %v", err)
+		os.Exit(1)
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		l.Warnf("server: listening on %s: %v", *listen, err)
+		os.Exit(1)
+	}
+
+	grpcServer := grpc.NewServer()
+	syncapi.RegisterSightingServiceServer(grpcServer, &syncapi.Server{Store: store})
+
+	l.Infof("server: serving SightingService on %s", *listen)
+	if err := grpcServer.Serve(lis); err != nil {
+		l.Warnf("server: %v", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,177 @@
+package syncapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/RandomByte/onion-bt-wardriving/internal/log"
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+)
+
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// Client batches locally observed devices and ships them to a remote
+// collector, retrying failed pushes with exponential backoff.
+type Client struct {
+	conn   *grpc.ClientConn
+	client SightingServiceClient
+
+	mu      sync.Mutex
+	pending []persistence.Observation
+}
+
+// Dial connects to a collector's SightingService at addr.
+func Dial(addr string) (*Client, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("syncapi: dialing %s: %v", addr, err)
+	}
+
+	return &Client{conn: conn, client: NewSightingServiceClient(conn)}, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Enqueue marks mac/device as needing to be pushed to the collector on
+// the next flush.
+func (c *Client) Enqueue(mac string, device persistence.Device) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(c.pending, persistence.Observation{MAC: mac, Device: device})
+}
+
+// Run flushes pending observations to the collector every interval
+// until ctx is canceled, retrying failed pushes with exponential
+// backoff instead of dropping them.
+func (c *Client) Run(ctx context.Context, interval time.Duration) {
+	backoff := initialBackoff
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			batch := c.drain()
+			if len(batch) == 0 {
+				continue
+			}
+
+			if err := c.push(ctx, batch); err != nil {
+				log.L.Warnf("syncapi: pushing %d sightings: %v (retrying in %s)", len(batch), err, backoff)
+				c.requeue(batch)
+
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+
+			backoff = initialBackoff
+		}
+	}
+}
+
+func (c *Client) drain() []persistence.Observation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch := c.pending
+	c.pending = nil
+	return batch
+}
+
+func (c *Client) requeue(batch []persistence.Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(batch, c.pending...)
+}
+
+func (c *Client) push(ctx context.Context, batch []persistence.Observation) error {
+	stream, err := c.client.Push(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, obs := range batch {
+		sighting, err := sightingFromObservation(obs)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(sighting); err != nil {
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// Resume subscribes to the collector starting at cursor (the zero
+// time.Time for a full resync), applies every sighting it streams back
+// into store, and returns the cursor to resume from next time - so a
+// node that was offline doesn't have to re-receive everything.
+//
+// onAdvance, if non-nil, is called with the cursor after each applied
+// sighting, so a caller can persist progress incrementally rather than
+// losing it all to a stream that drops partway through a large backlog.
+func (c *Client) Resume(ctx context.Context, cursor time.Time, store persistence.Store, onAdvance func(time.Time)) (time.Time, error) {
+	since, err := cursor.MarshalBinary()
+	if err != nil {
+		return cursor, fmt.Errorf("syncapi: encoding resume cursor: %v", err)
+	}
+
+	stream, err := c.client.Subscribe(ctx, &SubscribeRequest{Since: since})
+	if err != nil {
+		return cursor, err
+	}
+
+	for {
+		sighting, err := stream.Recv()
+		if err == io.EOF {
+			return cursor, nil
+		}
+		if err != nil {
+			return cursor, err
+		}
+
+		obs, err := observationFromSighting(sighting)
+		if err != nil {
+			return cursor, err
+		}
+
+		if err := store.PutDevice(obs.MAC, obs.Device); err != nil {
+			return cursor, err
+		}
+
+		if seen := time.Unix(obs.Device.LastSeen, 0); seen.After(cursor) {
+			cursor = seen
+			if onAdvance != nil {
+				onAdvance(cursor)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package syncapi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+)
+
+// sightingFromObservation converts a stored observation to its wire
+// representation.
+func sightingFromObservation(obs persistence.Observation) (*Sighting, error) {
+	lastSeen, err := time.Unix(obs.Device.LastSeen, 0).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("syncapi: encoding last_seen: %v", err)
+	}
+
+	manufacturerData := make(map[uint32][]byte, len(obs.Device.ManufacturerData))
+	for id, data := range obs.Device.ManufacturerData {
+		manufacturerData[uint32(id)] = data
+	}
+
+	return &Sighting{
+		Mac:              obs.MAC,
+		Name:             obs.Device.Name,
+		Count:            int32(obs.Device.Count),
+		LastSeen:         lastSeen,
+		Rssi:             int32(obs.Device.RSSI),
+		TxPower:          int32(obs.Device.TxPower),
+		AddressType:      obs.Device.AddressType,
+		ServiceUuids:     obs.Device.ServiceUUIDs,
+		ManufacturerData: manufacturerData,
+		Lat:              obs.Device.Lat,
+		Lon:              obs.Device.Lon,
+		Alt:              obs.Device.Alt,
+		FixTime:          obs.Device.FixTime,
+		NoFix:            obs.Device.NoFix,
+		Hdop:             obs.Device.HDOP,
+		FirstSeen:        obs.Device.FirstSeen,
+	}, nil
+}
+
+// observationFromSighting is sightingFromObservation's inverse.
+func observationFromSighting(s *Sighting) (persistence.Observation, error) {
+	var lastSeen time.Time
+	if err := lastSeen.UnmarshalBinary(s.LastSeen); err != nil {
+		return persistence.Observation{}, fmt.Errorf("syncapi: decoding last_seen: %v", err)
+	}
+
+	manufacturerData := make(map[uint16][]byte, len(s.ManufacturerData))
+	for id, data := range s.ManufacturerData {
+		manufacturerData[uint16(id)] = data
+	}
+
+	return persistence.Observation{
+		MAC: s.Mac,
+		Device: persistence.Device{
+			Name:             s.Name,
+			Count:            int(s.Count),
+			LastSeen:         lastSeen.Unix(),
+			RSSI:             int16(s.Rssi),
+			TxPower:          int16(s.TxPower),
+			AddressType:      s.AddressType,
+			ServiceUUIDs:     s.ServiceUuids,
+			ManufacturerData: manufacturerData,
+			Lat:              s.Lat,
+			Lon:              s.Lon,
+			Alt:              s.Alt,
+			FixTime:          s.FixTime,
+			NoFix:            s.NoFix,
+			HDOP:             s.Hdop,
+			FirstSeen:        s.FirstSeen,
+		},
+	}, nil
+}
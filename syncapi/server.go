@@ -0,0 +1,69 @@
+package syncapi
+
+import (
+	"io"
+	"time"
+
+	"github.com/RandomByte/onion-bt-wardriving/persistence"
+)
+
+// Server implements SightingServiceServer on top of a Store, letting a
+// fleet of wardriving nodes push their observations into one place and
+// subscribe to each other's.
+type Server struct {
+	Store persistence.Store
+}
+
+// Push receives a stream of sightings and writes each straight into the
+// backing Store.
+func (s *Server) Push(stream SightingService_PushServer) error {
+	var received int32
+
+	for {
+		sighting, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&PushAck{Received: received})
+		}
+		if err != nil {
+			return err
+		}
+
+		obs, err := observationFromSighting(sighting)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Store.PutDevice(obs.MAC, obs.Device); err != nil {
+			return err
+		}
+		received++
+	}
+}
+
+// Subscribe streams every sighting at or after req.Since.
+func (s *Server) Subscribe(req *SubscribeRequest, stream SightingService_SubscribeServer) error {
+	var since time.Time
+	if len(req.Since) > 0 {
+		if err := since.UnmarshalBinary(req.Since); err != nil {
+			return err
+		}
+	}
+
+	observations, err := s.Store.IterateSince(since.Unix())
+	if err != nil {
+		return err
+	}
+
+	for obs := range observations {
+		sighting, err := sightingFromObservation(obs)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(sighting); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+// Hand-written stand-in for the protoc-gen-go/protoc-gen-go-grpc output
+// for sighting.proto - there's no working protoc toolchain in this
+// build environment, so these types and the client/server stubs below
+// are maintained by hand instead of regenerated. Keep them in sync with
+// sighting.proto if either changes.
+
+package syncapi
+
+import (
+	"context"
+
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+
+// Sighting is a single device observation, as pushed to or streamed
+// from a SightingService.
+type Sighting struct {
+	Mac         string `protobuf:"bytes,1,opt,name=mac" json:"mac,omitempty"`
+	Name        string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Count       int32  `protobuf:"varint,3,opt,name=count" json:"count,omitempty"`
+	// LastSeen is a time.Time encoded with MarshalBinary.
+	LastSeen    []byte `protobuf:"bytes,4,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+
+	Rssi             int32            `protobuf:"varint,5,opt,name=rssi" json:"rssi,omitempty"`
+	TxPower          int32            `protobuf:"varint,6,opt,name=tx_power,json=txPower" json:"tx_power,omitempty"`
+	AddressType      string           `protobuf:"bytes,7,opt,name=address_type,json=addressType" json:"address_type,omitempty"`
+	ServiceUuids     []string         `protobuf:"bytes,8,rep,name=service_uuids,json=serviceUuids" json:"service_uuids,omitempty"`
+	ManufacturerData map[uint32][]byte `protobuf:"bytes,9,rep,name=manufacturer_data,json=manufacturerData" json:"manufacturer_data,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+
+	Lat       float64 `protobuf:"fixed64,10,opt,name=lat" json:"lat,omitempty"`
+	Lon       float64 `protobuf:"fixed64,11,opt,name=lon" json:"lon,omitempty"`
+	Alt       float64 `protobuf:"fixed64,12,opt,name=alt" json:"alt,omitempty"`
+	FixTime   int64   `protobuf:"varint,13,opt,name=fix_time,json=fixTime" json:"fix_time,omitempty"`
+	NoFix     bool    `protobuf:"varint,14,opt,name=no_fix,json=noFix" json:"no_fix,omitempty"`
+	Hdop      float64 `protobuf:"fixed64,15,opt,name=hdop" json:"hdop,omitempty"`
+	FirstSeen int64   `protobuf:"varint,16,opt,name=first_seen,json=firstSeen" json:"first_seen,omitempty"`
+}
+
+func (m *Sighting) Reset()         { *m = Sighting{} }
+func (m *Sighting) String() string { return proto.CompactTextString(m) }
+func (*Sighting) ProtoMessage()    {}
+
+// PushAck acknowledges a completed Push stream.
+type PushAck struct {
+	Received int32 `protobuf:"varint,1,opt,name=received" json:"received,omitempty"`
+}
+
+func (m *PushAck) Reset()         { *m = PushAck{} }
+func (m *PushAck) String() string { return proto.CompactTextString(m) }
+func (*PushAck) ProtoMessage()    {}
+
+// SubscribeRequest asks a SightingService for every sighting at or
+// after Since.
+type SubscribeRequest struct {
+	// Since is a time.Time encoded with MarshalBinary.
+	Since []byte `protobuf:"bytes,1,opt,name=since" json:"since,omitempty"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*Sighting)(nil), "syncapi.Sighting")
+	proto.RegisterType((*PushAck)(nil), "syncapi.PushAck")
+	proto.RegisterType((*SubscribeRequest)(nil), "syncapi.SubscribeRequest")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// SightingServiceClient is the client API for SightingService.
+type SightingServiceClient interface {
+	Push(ctx context.Context, opts ...grpc.CallOption) (SightingService_PushClient, error)
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (SightingService_SubscribeClient, error)
+}
+
+type sightingServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSightingServiceClient(cc *grpc.ClientConn) SightingServiceClient {
+	return &sightingServiceClient{cc}
+}
+
+func (c *sightingServiceClient) Push(ctx context.Context, opts ...grpc.CallOption) (SightingService_PushClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SightingService_serviceDesc.Streams[0], "/syncapi.SightingService/Push", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &sightingServicePushClient{stream}, nil
+}
+
+type SightingService_PushClient interface {
+	Send(*Sighting) error
+	CloseAndRecv() (*PushAck, error)
+	grpc.ClientStream
+}
+
+type sightingServicePushClient struct {
+	grpc.ClientStream
+}
+
+func (x *sightingServicePushClient) Send(m *Sighting) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sightingServicePushClient) CloseAndRecv() (*PushAck, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PushAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sightingServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (SightingService_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SightingService_serviceDesc.Streams[1], "/syncapi.SightingService/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sightingServiceSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SightingService_SubscribeClient interface {
+	Recv() (*Sighting, error)
+	grpc.ClientStream
+}
+
+type sightingServiceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *sightingServiceSubscribeClient) Recv() (*Sighting, error) {
+	m := new(Sighting)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SightingServiceServer is the server API for SightingService.
+type SightingServiceServer interface {
+	Push(SightingService_PushServer) error
+	Subscribe(*SubscribeRequest, SightingService_SubscribeServer) error
+}
+
+func RegisterSightingServiceServer(s *grpc.Server, srv SightingServiceServer) {
+	s.RegisterService(&_SightingService_serviceDesc, srv)
+}
+
+func _SightingService_Push_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SightingServiceServer).Push(&sightingServicePushServer{stream})
+}
+
+type SightingService_PushServer interface {
+	SendAndClose(*PushAck) error
+	Recv() (*Sighting, error)
+	grpc.ServerStream
+}
+
+type sightingServicePushServer struct {
+	grpc.ServerStream
+}
+
+func (x *sightingServicePushServer) SendAndClose(m *PushAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sightingServicePushServer) Recv() (*Sighting, error) {
+	m := new(Sighting)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SightingService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SightingServiceServer).Subscribe(m, &sightingServiceSubscribeServer{stream})
+}
+
+type SightingService_SubscribeServer interface {
+	Send(*Sighting) error
+	grpc.ServerStream
+}
+
+type sightingServiceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *sightingServiceSubscribeServer) Send(m *Sighting) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _SightingService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "syncapi.SightingService",
+	HandlerType: (*SightingServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Push",
+			Handler:       _SightingService_Push_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _SightingService_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sighting.proto",
+}